@@ -0,0 +1,109 @@
+package ingress
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/psrpc"
+)
+
+var (
+	metricsOnce sync.Once
+
+	requestLatency   *prometheus.HistogramVec
+	requestTimeouts  *prometheus.CounterVec
+	requestErrors    *prometheus.CounterVec
+	responsesTotal   *prometheus.CounterVec
+	requestsInFlight prometheus.Gauge
+	updatesPublished prometheus.Counter
+)
+
+const (
+	methodSendRequest               = "SendRequest"
+	methodSendGetIngressInfoRequest = "SendGetIngressInfoRequest"
+)
+
+// registerMetrics registers the ingress RPC collectors with the default
+// Prometheus registry. It's safe to call from every RPC constructor: only
+// the first call takes effect.
+func registerMetrics() {
+	metricsOnce.Do(func() {
+		requestLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "livekit",
+			Subsystem: "ingress_rpc",
+			Name:      "request_duration_seconds",
+			Help:      "Round-trip latency of ingress RPC requests, by method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method"})
+
+		requestTimeouts = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "livekit",
+			Subsystem: "ingress_rpc",
+			Name:      "request_timeouts_total",
+			Help:      "Number of ingress RPC requests that timed out waiting for a response, by method.",
+		}, []string{"method"})
+
+		requestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "livekit",
+			Subsystem: "ingress_rpc",
+			Name:      "request_errors_total",
+			Help:      "Number of ingress RPC requests that failed before a response was received, by method.",
+		}, []string{"method"})
+
+		responsesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "livekit",
+			Subsystem: "ingress_rpc",
+			Name:      "responses_total",
+			Help:      "Number of ingress RPC responses received, by method.",
+		}, []string{"method"})
+
+		requestsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "livekit",
+			Subsystem: "ingress_rpc",
+			Name:      "requests_in_flight",
+			Help:      "Number of ingress RPC requests currently awaiting a response.",
+		})
+
+		updatesPublished = prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "livekit",
+			Subsystem: "ingress_rpc",
+			Name:      "updates_published_total",
+			Help:      "Number of ingress state updates published.",
+		})
+
+		prometheus.MustRegister(
+			requestLatency,
+			requestTimeouts,
+			requestErrors,
+			responsesTotal,
+			requestsInFlight,
+			updatesPublished,
+		)
+	})
+}
+
+// ingressMetricsInterceptor records in-flight, latency, error and response
+// metrics for psrpc-based ingress servers (handlerServer, internalServer),
+// using the same collectors as sendRequest on the Redis/RabbitMQ RPC path.
+// Unlike sendRequest, it observes latency for failed calls too, and it never
+// counts timeouts.
+func ingressMetricsInterceptor(ctx context.Context, req proto.Message, info psrpc.RPCInfo, handler psrpc.ServerHandler) (proto.Message, error) {
+	requestsInFlight.Inc()
+	defer requestsInFlight.Dec()
+	start := time.Now()
+
+	resp, err := handler(ctx, req)
+
+	requestLatency.WithLabelValues(info.Method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		requestErrors.WithLabelValues(info.Method).Inc()
+	} else {
+		responsesTotal.WithLabelValues(info.Method).Inc()
+	}
+
+	return resp, err
+}
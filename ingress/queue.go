@@ -0,0 +1,59 @@
+package ingress
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// QueuedRequest wraps an IngressRequest with the queue-specific handle a
+// RequestQueue needs to acknowledge delivery.
+type QueuedRequest struct {
+	Request *livekit.IngressRequest
+	AckID   string
+}
+
+// RequestQueue provides durable, at-least-once delivery of ingress requests,
+// so a request published while no worker is subscribed isn't silently
+// dropped the way a plain pub/sub publish would be. Implementations retain
+// an enqueued request until Ack is called, redelivering it otherwise.
+type RequestQueue interface {
+	// Enqueue durably stores req for the ingress worker handling ingressID.
+	Enqueue(ctx context.Context, ingressID string, req *livekit.IngressRequest) error
+	// Consume starts delivering pending and future requests for ingressID.
+	Consume(ctx context.Context, ingressID string) (<-chan *QueuedRequest, error)
+	// Ack marks req as handled, removing it from the queue.
+	Ack(ctx context.Context, req *QueuedRequest) error
+	// Backlog returns the number of unacked requests queued for ingressID.
+	Backlog(ctx context.Context, ingressID string) (int64, error)
+}
+
+// QueueConfig selects and configures the RequestQueue backend. Exactly one
+// of Redis or LevelDBPath should be set.
+type QueueConfig struct {
+	Redis         redis.UniversalClient
+	RedisConsumer string
+
+	LevelDBPath string
+}
+
+// NewRequestQueue builds a RequestQueue backed by whichever backend is
+// configured in conf: Redis Streams for multi-node deployments, or an
+// on-disk LevelDB queue for single-node ones with no shared Redis.
+func NewRequestQueue(conf *QueueConfig) (RequestQueue, error) {
+	if conf == nil {
+		return nil, errors.New("rpc: no queue backend configured")
+	}
+
+	switch {
+	case conf.Redis != nil:
+		return NewRedisStreamQueue(conf.Redis, conf.RedisConsumer), nil
+	case conf.LevelDBPath != "":
+		return NewLevelDBQueue(conf.LevelDBPath)
+	default:
+		return nil, errors.New("rpc: no queue backend configured")
+	}
+}
@@ -0,0 +1,140 @@
+package ingress
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+)
+
+// requestStreamGroup is the consumer group all ingress nodes share, keyed
+// per-stream (one stream per ingress ID). An unacked request stays in the
+// group's pending list and is redelivered when a consumer with the same name
+// reads the stream again (see consumeLoop); it is not claimed by other
+// consumers of the group.
+const requestStreamGroup = "ingress"
+
+// RedisStreamQueue implements RequestQueue on top of Redis Streams.
+type RedisStreamQueue struct {
+	rc       redis.UniversalClient
+	consumer string
+}
+
+func NewRedisStreamQueue(rc redis.UniversalClient, consumer string) *RedisStreamQueue {
+	return &RedisStreamQueue{rc: rc, consumer: consumer}
+}
+
+func (q *RedisStreamQueue) stream(ingressID string) string {
+	return requestChannelPrefix + ingressID
+}
+
+func (q *RedisStreamQueue) Enqueue(ctx context.Context, ingressID string, req *livekit.IngressRequest) error {
+	body, err := proto.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	return q.rc.XAdd(ctx, &redis.XAddArgs{
+		Stream: q.stream(ingressID),
+		Values: map[string]interface{}{"body": body},
+	}).Err()
+}
+
+func (q *RedisStreamQueue) Consume(ctx context.Context, ingressID string) (<-chan *QueuedRequest, error) {
+	stream := q.stream(ingressID)
+
+	err := q.rc.XGroupCreateMkStream(ctx, stream, requestStreamGroup, "0").Err()
+	if err != nil && !isBusyGroupErr(err) {
+		return nil, err
+	}
+
+	out := make(chan *QueuedRequest)
+	go q.consumeLoop(ctx, stream, out)
+
+	return out, nil
+}
+
+func (q *RedisStreamQueue) consumeLoop(ctx context.Context, stream string, out chan<- *QueuedRequest) {
+	defer close(out)
+
+	// Start with this consumer's own pending entries - delivered earlier but
+	// never acked - and switch to new entries once they've all been re-read.
+	id := "0"
+	for {
+		res, err := q.rc.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    requestStreamGroup,
+			Consumer: q.consumer,
+			Streams:  []string{stream, id},
+			Count:    1,
+			Block:    0,
+		}).Result()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logger.Errorw("failed to read ingress request stream", err, "stream", stream)
+			continue
+		}
+
+		if id != ">" && (len(res) == 0 || len(res[0].Messages) == 0) {
+			id = ">"
+			continue
+		}
+
+		for _, s := range res {
+			for _, msg := range s.Messages {
+				if id != ">" {
+					id = msg.ID
+				}
+				body, _ := msg.Values["body"].(string)
+				req := &livekit.IngressRequest{}
+				if err := proto.Unmarshal([]byte(body), req); err != nil {
+					logger.Errorw("failed to unmarshal queued ingress request", err, "id", msg.ID)
+					continue
+				}
+
+				select {
+				case out <- &QueuedRequest{Request: req, AckID: stream + ":" + msg.ID}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}
+}
+
+func (q *RedisStreamQueue) Ack(ctx context.Context, req *QueuedRequest) error {
+	stream, id, err := splitAckID(req.AckID)
+	if err != nil {
+		return err
+	}
+	return q.rc.XAck(ctx, stream, requestStreamGroup, id).Err()
+}
+
+// Backlog returns the size of the group's pending list: requests delivered
+// to a consumer and not yet acked. Requests no consumer has read yet are not
+// counted.
+func (q *RedisStreamQueue) Backlog(ctx context.Context, ingressID string) (int64, error) {
+	res, err := q.rc.XPending(ctx, q.stream(ingressID), requestStreamGroup).Result()
+	if err != nil {
+		return 0, err
+	}
+	return res.Count, nil
+}
+
+func isBusyGroupErr(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "BUSYGROUP")
+}
+
+func splitAckID(ackID string) (stream string, id string, err error) {
+	idx := strings.LastIndex(ackID, ":")
+	if idx < 0 {
+		return "", "", errors.New("rpc: invalid ack id")
+	}
+	return ackID[:idx], ackID[idx+1:], nil
+}
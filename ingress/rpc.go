@@ -3,6 +3,7 @@ package ingress
 import (
 	"context"
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -28,9 +29,23 @@ const (
 // RPCClient is used by LiveKit Server
 type RPCClient interface {
 	// GetUpdateChannel returns a subscription for ingress info updates
+	//
+	// Deprecated: use Events instead, which streams per-ingress updates
+	// without requiring callers to poll or filter a shared channel.
 	GetUpdateChannel(ctx context.Context) (utils.PubSub, error)
 	// GetEntityChannel returns a subscription for entity requests
 	GetEntityChannel(ctx context.Context) (utils.PubSub, error)
+	// Events streams the full lifecycle of an ingress - state transitions,
+	// bitrate samples, disconnects - as they happen. The last known state,
+	// if any, is replayed immediately on subscribe. The returned channel is
+	// closed when ctx is done.
+	//
+	// IngressState carries no sequence number, so an update dropped for a
+	// subscriber that isn't keeping up is only logged (see eventDemuxer), and
+	// ordering is not checked at all; callers have no
+	// way to detect a gap after a reconnect. Adding that requires a new
+	// field on IngressState, which is out of this package's scope.
+	Events(ctx context.Context, ingressID string) (<-chan *livekit.IngressState, error)
 	// SendRequest sends a request to all available instances
 	SendRequest(ctx context.Context, req *livekit.IngressRequest) (*livekit.IngressState, error)
 	// SendResponse returns a GetIngressInfo response
@@ -43,7 +58,10 @@ type RPCServer interface {
 	IngressSubscription(ctx context.Context, ingressID string) (utils.PubSub, error)
 	// SendResponse returns an RPC response
 	SendResponse(ctx context.Context, request *livekit.IngressRequest, state *livekit.IngressState, err error) error
-	// SendUpdate sends an ingress info update
+	// SendUpdate sends an ingress info update.
+	//
+	// Deprecated: kept for GetUpdateChannel compatibility; new callers
+	// should rely on RPCClient.Events to consume these updates.
 	SendUpdate(ctx context.Context, ingressId string, state *livekit.IngressState) error
 	// SendGetIngressInfoRequest sends a request to all available instances
 	SendGetIngressInfoRequest(ctx context.Context, req *livekit.GetIngressInfoRequest) (*livekit.GetIngressInfoResponse, error)
@@ -54,9 +72,56 @@ type RPC interface {
 	RPCServer
 }
 
+// MessageBus abstracts the pub/sub transport backing ingress RPC, so that
+// RedisRPC and RabbitMQRPC can share the same request/response plumbing
+// instead of each hard-wiring a specific broker.
+type MessageBus interface {
+	Publish(ctx context.Context, channel string, msg proto.Message) error
+	Subscribe(ctx context.Context, channel string) (utils.PubSub, error)
+	SubscribeQueue(ctx context.Context, channel string) (utils.PubSub, error)
+}
+
+// Config selects and configures the transport used to build an RPC. Exactly
+// one of Redis, RedisConfig or RabbitMQ should be set.
+type Config struct {
+	Redis       redis.UniversalClient
+	RedisConfig *RedisConfig
+	RabbitMQ    *RabbitMQConfig
+}
+
+// NewRPC builds an RPC backed by whichever transport is configured in conf.
+func NewRPC(nodeID livekit.NodeID, conf *Config) (RPC, error) {
+	if conf == nil {
+		return nil, errors.New("rpc: no transport configured")
+	}
+
+	switch {
+	case conf.RabbitMQ != nil:
+		return NewRabbitMQRPC(nodeID, conf.RabbitMQ)
+	case conf.RedisConfig != nil:
+		return NewRedisRPCFromConfig(nodeID, conf.RedisConfig)
+	case conf.Redis != nil:
+		return NewRedisRPC(nodeID, conf.Redis), nil
+	default:
+		return nil, errors.New("rpc: no transport configured")
+	}
+}
+
 type RedisRPC struct {
 	nodeID livekit.NodeID
-	bus    *utils.RedisMessageBus
+	bus    MessageBus
+	// readBus, when set by NewRedisRPCFromConfig, is used for Subscribe/
+	// SubscribeQueue instead of bus, routing subscriptions to a read
+	// replica while publishes still go through bus. See subBus.
+	readBus MessageBus
+	events  *eventDemuxer
+
+	// queue, when set, makes SendRequest enqueue onto it instead of
+	// publishing requests directly, and SendResponse ack it once the
+	// request has been answered. See NewRedisRPCWithQueue.
+	queue  RequestQueue
+	ackMu  sync.Mutex
+	ackIDs map[string]string // IngressRequest.RequestId -> QueuedRequest.AckID
 }
 
 func NewRedisRPC(nodeID livekit.NodeID, rc redis.UniversalClient) RPC {
@@ -64,25 +129,66 @@ func NewRedisRPC(nodeID livekit.NodeID, rc redis.UniversalClient) RPC {
 		return nil
 	}
 
-	bus := utils.NewRedisMessageBus(rc)
-	return &RedisRPC{
+	registerMetrics()
+
+	r := &RedisRPC{
 		nodeID: nodeID,
-		bus:    bus.(*utils.RedisMessageBus),
+		bus:    utils.NewRedisMessageBus(rc).(*utils.RedisMessageBus),
 	}
+	r.events = newEventDemuxer(func(ctx context.Context) (utils.PubSub, error) {
+		return r.subBus().SubscribeQueue(ctx, updateChannel)
+	})
+	return r
+}
+
+// NewRedisRPCWithQueue builds a RedisRPC that routes ingress requests
+// through queue for durable, at-least-once delivery, instead of publishing
+// them to a request channel that's lost if no worker happens to be
+// subscribed. Updates, entity requests and responses are unaffected, since
+// they're either ephemeral or already replayed via Events.
+func NewRedisRPCWithQueue(nodeID livekit.NodeID, rc redis.UniversalClient, queue RequestQueue) RPC {
+	r, ok := NewRedisRPC(nodeID, rc).(*RedisRPC)
+	if !ok {
+		return nil
+	}
+
+	r.queue = queue
+	r.ackIDs = make(map[string]string)
+
+	return r
+}
+
+// subBus returns the MessageBus used for Subscribe/SubscribeQueue: readBus
+// if NewRedisRPCFromConfig built one for replica reads, otherwise bus.
+// Publishes always go through bus directly.
+func (r *RedisRPC) subBus() MessageBus {
+	if r.readBus != nil {
+		return r.readBus
+	}
+	return r.bus
 }
 
 func (r *RedisRPC) GetUpdateChannel(ctx context.Context) (utils.PubSub, error) {
-	return r.bus.SubscribeQueue(context.Background(), updateChannel)
+	return r.subBus().SubscribeQueue(context.Background(), updateChannel)
+}
+
+func (r *RedisRPC) Events(ctx context.Context, ingressID string) (<-chan *livekit.IngressState, error) {
+	return r.events.Events(ctx, ingressID)
 }
 
 func (r *RedisRPC) sendRequest(
 	ctx context.Context,
 	requestID string,
-	channel string,
-	request proto.Message,
+	method string,
+	publish func(ctx context.Context) error,
 	resp proto.Message) (proto.Message, error) {
-	sub, err := r.bus.Subscribe(ctx, responseChannel(requestID))
+	requestsInFlight.Inc()
+	defer requestsInFlight.Dec()
+	start := time.Now()
+
+	sub, err := r.subBus().Subscribe(ctx, responseChannel(requestID))
 	if err != nil {
+		requestErrors.WithLabelValues(method).Inc()
 		return nil, err
 	}
 	defer func() {
@@ -92,8 +198,8 @@ func (r *RedisRPC) sendRequest(
 		}
 	}()
 
-	err = r.bus.Publish(ctx, channel, request)
-	if err != nil {
+	if err := publish(ctx); err != nil {
+		requestErrors.WithLabelValues(method).Inc()
 		return nil, err
 	}
 
@@ -101,27 +207,37 @@ func (r *RedisRPC) sendRequest(
 	case raw := <-sub.Channel():
 		err := proto.Unmarshal(sub.Payload(raw), resp)
 		if err != nil {
+			requestErrors.WithLabelValues(method).Inc()
 			return nil, err
 		} else {
+			requestLatency.WithLabelValues(method).Observe(time.Since(start).Seconds())
+			responsesTotal.WithLabelValues(method).Inc()
 			return resp, nil
 		}
 
 	case <-time.After(requestTimeout):
+		requestTimeouts.WithLabelValues(method).Inc()
 		return nil, ErrNoResponse
 	}
 }
 
 func (r *RedisRPC) SendRequest(ctx context.Context, req *livekit.IngressRequest) (*livekit.IngressState, error) {
 	requestID := utils.NewGuid(utils.RPCPrefix)
-	var channel string
-	var err error
 
 	req.RequestId = requestID
 	req.SenderId = string(r.nodeID)
-	channel = requestChannel(req.IngressId)
 	resp := &livekit.IngressResponse{}
 
-	_, err = r.sendRequest(ctx, requestID, channel, req, resp)
+	publish := func(ctx context.Context) error {
+		return r.bus.Publish(ctx, requestChannel(req.IngressId), req)
+	}
+	if r.queue != nil {
+		publish = func(ctx context.Context) error {
+			return r.queue.Enqueue(ctx, req.IngressId, req)
+		}
+	}
+
+	_, err := r.sendRequest(ctx, requestID, methodSendRequest, publish, resp)
 	if err != nil {
 		return nil, err
 	} else if resp.Error != "" {
@@ -133,16 +249,15 @@ func (r *RedisRPC) SendRequest(ctx context.Context, req *livekit.IngressRequest)
 
 func (r *RedisRPC) SendGetIngressInfoRequest(ctx context.Context, req *livekit.GetIngressInfoRequest) (*livekit.GetIngressInfoResponse, error) {
 	requestID := utils.NewGuid(utils.RPCPrefix)
-	var channel string
-	var err error
 
 	req.RequestId = requestID
 	req.SenderId = string(r.nodeID)
 	req.SentAt = time.Now().UnixNano()
-	channel = entityChannel
 	resp := &livekit.GetIngressInfoResponse{}
 
-	_, err = r.sendRequest(ctx, requestID, channel, req, resp)
+	_, err := r.sendRequest(ctx, requestID, methodSendGetIngressInfoRequest, func(ctx context.Context) error {
+		return r.bus.Publish(ctx, entityChannel, req)
+	}, resp)
 	if err != nil {
 		return nil, err
 	} else if resp.Error != "" {
@@ -151,8 +266,41 @@ func (r *RedisRPC) SendGetIngressInfoRequest(ctx context.Context, req *livekit.G
 		return resp, nil
 	}
 }
+
+// ConsumeRequests starts delivering requests durably queued for ingressID.
+// Use this instead of IngressSubscription when the RPC was built with
+// NewRedisRPCWithQueue; SendResponse acks the corresponding queue entry
+// once the request has been answered.
+func (r *RedisRPC) ConsumeRequests(ctx context.Context, ingressID string) (<-chan *livekit.IngressRequest, error) {
+	if r.queue == nil {
+		return nil, errors.New("rpc: no request queue configured")
+	}
+
+	queued, err := r.queue.Consume(ctx, ingressID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan *livekit.IngressRequest)
+	go func() {
+		defer close(out)
+		for q := range queued {
+			r.ackMu.Lock()
+			r.ackIDs[q.Request.RequestId] = q.AckID
+			r.ackMu.Unlock()
+
+			select {
+			case out <- q.Request:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
 func (r *RedisRPC) IngressSubscription(ctx context.Context, ingressID string) (utils.PubSub, error) {
-	return r.bus.Subscribe(ctx, requestChannel(ingressID))
+	return r.subBus().Subscribe(ctx, requestChannel(ingressID))
 }
 
 func (r *RedisRPC) SendResponse(ctx context.Context, req *livekit.IngressRequest, state *livekit.IngressState, err error) error {
@@ -165,6 +313,19 @@ func (r *RedisRPC) SendResponse(ctx context.Context, req *livekit.IngressRequest
 		res.Error = err.Error()
 	}
 
+	if r.queue != nil {
+		r.ackMu.Lock()
+		ackID, ok := r.ackIDs[req.RequestId]
+		delete(r.ackIDs, req.RequestId)
+		r.ackMu.Unlock()
+
+		if ok {
+			if ackErr := r.queue.Ack(ctx, &QueuedRequest{Request: req, AckID: ackID}); ackErr != nil {
+				logger.Errorw("failed to ack queued ingress request", ackErr, "requestID", req.RequestId)
+			}
+		}
+	}
+
 	return r.bus.Publish(ctx, responseChannel(res.RequestId), res)
 }
 
@@ -179,6 +340,7 @@ func (r *RedisRPC) SendGetIngressInfoResponse(ctx context.Context, req *livekit.
 }
 
 func (r *RedisRPC) SendUpdate(ctx context.Context, ingressId string, state *livekit.IngressState) error {
+	updatesPublished.Inc()
 	return r.bus.Publish(ctx, updateChannel, &livekit.UpdateIngressStateRequest{
 		IngressId: ingressId,
 		State:     state,
@@ -186,7 +348,7 @@ func (r *RedisRPC) SendUpdate(ctx context.Context, ingressId string, state *live
 }
 
 func (r *RedisRPC) GetEntityChannel(ctx context.Context) (utils.PubSub, error) {
-	return r.bus.SubscribeQueue(ctx, entityChannel)
+	return r.subBus().SubscribeQueue(ctx, entityChannel)
 }
 
 func requestChannel(ingressID string) string {
@@ -216,6 +378,8 @@ type handlerServer struct {
 }
 
 func NewHandlerServer(nodeID livekit.NodeID, bus psrpc.MessageBus) HandlerServer {
+	registerMetrics()
+
 	return &handlerServer{
 		nodeID: nodeID,
 		bus:    bus,
@@ -224,11 +388,11 @@ func NewHandlerServer(nodeID livekit.NodeID, bus psrpc.MessageBus) HandlerServer
 
 func (s *handlerServer) SetServerImpl(impl HandlerServerImpl) error {
 	serverID := string(s.nodeID)
-	handlerServer, err := rpc.NewIngressHandlerServer(serverID, impl, s.bus)
+	handlerServer, err := rpc.NewIngressHandlerServer(serverID, impl, s.bus, psrpc.WithServerInterceptors(ingressMetricsInterceptor))
 	if err != nil {
 		return err
 	}
-	updateServer, err := rpc.NewIngressUpdateServer(serverID, impl, s.bus)
+	updateServer, err := rpc.NewIngressUpdateServer(serverID, impl, s.bus, psrpc.WithServerInterceptors(ingressMetricsInterceptor))
 	if err != nil {
 		return err
 	}
@@ -270,6 +434,8 @@ type internalServer struct {
 }
 
 func NewInternalServer(nodeID livekit.NodeID, bus psrpc.MessageBus) (InternalServer, error) {
+	registerMetrics()
+
 	entityClient, err := rpc.NewIngressEntityClient(string(nodeID), bus)
 	if err != nil {
 		return nil, err
@@ -284,11 +450,11 @@ func NewInternalServer(nodeID livekit.NodeID, bus psrpc.MessageBus) (InternalSer
 
 func (s *internalServer) SetServerImpl(impl InternalServerImpl) error {
 	serverID := string(s.nodeID)
-	internalServer, err := rpc.NewIngressInternalServer(serverID, impl, s.bus)
+	internalServer, err := rpc.NewIngressInternalServer(serverID, impl, s.bus, psrpc.WithServerInterceptors(ingressMetricsInterceptor))
 	if err != nil {
 		return err
 	}
-	updateServer, err := rpc.NewIngressUpdateServer(serverID, impl, s.bus)
+	updateServer, err := rpc.NewIngressUpdateServer(serverID, impl, s.bus, psrpc.WithServerInterceptors(ingressMetricsInterceptor))
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,164 @@
+package ingress
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils"
+)
+
+// eventsBufferSize bounds the per-ingress channel returned by Events, so a
+// slow consumer can't block delivery to the rest of the subscribers.
+const eventsBufferSize = 16
+
+// eventSub is a single Events() subscription, tracked so it can be found
+// and removed from eventDemuxer.subs once the caller's context is done.
+type eventSub struct {
+	ingressID string
+	ch        chan *livekit.IngressState
+}
+
+// eventDemuxer fans a single shared update subscription out into bounded,
+// per-ingress channels, so RPCClient.Events callers don't have to filter
+// the full update stream themselves.
+type eventDemuxer struct {
+	subscribe func(ctx context.Context) (utils.PubSub, error)
+
+	mu      sync.Mutex
+	started bool
+	// current is the upstream subscription run is draining, kept so
+	// Resubscribe can close it and force run to exit and reconnect.
+	current   utils.PubSub
+	lastState map[string]*livekit.IngressState
+	// lastSeq counts deliveries per ingress for the drop-warning log in run.
+	// It is never transmitted - IngressState has no sequence field - so it
+	// cannot be used by callers of Events to detect a gap after a reconnect.
+	lastSeq map[string]uint64
+	subs    map[string][]*eventSub
+}
+
+func newEventDemuxer(subscribe func(ctx context.Context) (utils.PubSub, error)) *eventDemuxer {
+	return &eventDemuxer{
+		subscribe: subscribe,
+		lastState: make(map[string]*livekit.IngressState),
+		lastSeq:   make(map[string]uint64),
+		subs:      make(map[string][]*eventSub),
+	}
+}
+
+// ensureStarted subscribes to the shared update stream on first use. A
+// failed attempt is not cached, so a transient broker outage doesn't
+// permanently disable Events for the rest of the process's lifetime.
+func (d *eventDemuxer) ensureStarted(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.started {
+		return nil
+	}
+
+	sub, err := d.subscribe(ctx)
+	if err != nil {
+		return err
+	}
+
+	d.started = true
+	d.current = sub
+	go d.run(sub)
+	return nil
+}
+
+// Resubscribe drops the current upstream subscription, if any, and
+// reconnects. Existing Events() channels and the last-known state per
+// ingress are left alone; only the upstream feed is replaced. Callers use
+// this when an out-of-band signal - a Sentinel failover, say - means the
+// old connection may be pointed at a stale address.
+func (d *eventDemuxer) Resubscribe(ctx context.Context) error {
+	d.mu.Lock()
+	old := d.current
+	d.started = false
+	d.current = nil
+	d.mu.Unlock()
+
+	if old != nil {
+		if err := old.Close(); err != nil {
+			logger.Errorw("failed to close ingress event subscription during resubscribe", err)
+		}
+	}
+
+	return d.ensureStarted(ctx)
+}
+
+// Events returns a channel that immediately replays the last known state
+// for ingressID, if any, then streams subsequent updates as they arrive.
+// The channel is closed and unregistered once ctx is done.
+func (d *eventDemuxer) Events(ctx context.Context, ingressID string) (<-chan *livekit.IngressState, error) {
+	if err := d.ensureStarted(ctx); err != nil {
+		return nil, err
+	}
+
+	sub := &eventSub{
+		ingressID: ingressID,
+		ch:        make(chan *livekit.IngressState, eventsBufferSize),
+	}
+
+	d.mu.Lock()
+	if last, ok := d.lastState[ingressID]; ok {
+		sub.ch <- last
+	}
+	d.subs[ingressID] = append(d.subs[ingressID], sub)
+	d.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		d.unsubscribe(sub)
+	}()
+
+	return sub.ch, nil
+}
+
+// unsubscribe removes sub and closes its channel. Both happen under d.mu,
+// the same lock run() holds while sending, so a send can never race a
+// close: run() either finds sub still registered and delivers to it, or
+// finds it already gone and skips it.
+func (d *eventDemuxer) unsubscribe(sub *eventSub) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	subs := d.subs[sub.ingressID]
+	for i, s := range subs {
+		if s == sub {
+			d.subs[sub.ingressID] = append(subs[:i], subs[i+1:]...)
+			break
+		}
+	}
+	close(sub.ch)
+}
+
+func (d *eventDemuxer) run(sub utils.PubSub) {
+	for raw := range sub.Channel() {
+		update := &livekit.UpdateIngressStateRequest{}
+		if err := proto.Unmarshal(sub.Payload(raw), update); err != nil {
+			logger.Errorw("failed to unmarshal ingress state update", err)
+			continue
+		}
+
+		d.mu.Lock()
+		d.lastState[update.IngressId] = update.State
+		d.lastSeq[update.IngressId]++
+		seq := d.lastSeq[update.IngressId]
+
+		for _, s := range d.subs[update.IngressId] {
+			select {
+			case s.ch <- update.State:
+			default:
+				logger.Warnw("dropping ingress state update, subscriber not keeping up", nil, "ingressID", update.IngressId, "sequence", seq)
+			}
+		}
+		d.mu.Unlock()
+	}
+}
@@ -0,0 +1,441 @@
+package ingress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/utils"
+)
+
+// RabbitMQConfig configures the RabbitMQ transport for RabbitMQRPC.
+type RabbitMQConfig struct {
+	URL string
+}
+
+// RabbitMQRPC implements RPC over RabbitMQ, giving deployments that already
+// run RabbitMQ a first-class ingress transport alongside RedisRPC.
+//
+// updateChannel/entityChannel are published to fanout exchanges, per-ingress
+// request channels are published to direct exchanges routed by ingress ID,
+// and all outgoing requests share one exclusive, auto-delete reply queue per
+// RabbitMQRPC, with replies matched to requests by correlation ID, so
+// sendRequest can block on that single queue instead of subscribing
+// per-request. entityChannel's subscribers share one named queue so only
+// one of them handles a given GetIngressInfo request, matching the
+// single-consumer semantics utils.MessageBus.SubscribeQueue gives the Redis
+// transport; updateChannel fans out to every subscriber, since eventDemuxer
+// needs to see every update to filter it locally.
+type RabbitMQRPC struct {
+	nodeID livekit.NodeID
+	conn   *amqp.Connection
+	ch     *amqp.Channel
+
+	// pubMu serializes every use of ch: amqp091 channels aren't safe for
+	// concurrent use, and exchange declaration, SendRequest, SendUpdate and
+	// replyTo all publish or declare on this one channel.
+	pubMu             sync.Mutex
+	declaredExchanges map[string]struct{}
+
+	mu        sync.Mutex
+	replyQ    amqp.Queue
+	replyCons <-chan amqp.Delivery
+	pending   map[string]chan *amqp.Delivery
+	// replyAddrs maps a request's correlation ID to the reply queue named
+	// in its ReplyTo field, so SendResponse/SendGetIngressInfoResponse can
+	// route the answer back to the right queue instead of the sender's
+	// node ID, which names no queue at all.
+	replyAddrs map[string]string
+
+	events *eventDemuxer
+}
+
+func NewRabbitMQRPC(nodeID livekit.NodeID, conf *RabbitMQConfig) (RPC, error) {
+	if conf == nil || conf.URL == "" {
+		return nil, errors.New("rpc: missing rabbitmq url")
+	}
+
+	registerMetrics()
+
+	conn, err := amqp.Dial(conf.URL)
+	if err != nil {
+		return nil, err
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	r := &RabbitMQRPC{
+		nodeID:            nodeID,
+		conn:              conn,
+		ch:                ch,
+		declaredExchanges: make(map[string]struct{}),
+		pending:           make(map[string]chan *amqp.Delivery),
+		replyAddrs:        make(map[string]string),
+	}
+
+	if err := r.declareFanout(updateChannel); err != nil {
+		return nil, err
+	}
+	if err := r.declareFanout(entityChannel); err != nil {
+		return nil, err
+	}
+	if err := r.setupReplyQueue(); err != nil {
+		return nil, err
+	}
+
+	r.events = newEventDemuxer(r.GetUpdateChannel)
+
+	return r, nil
+}
+
+func (r *RabbitMQRPC) declareFanout(name string) error {
+	r.pubMu.Lock()
+	defer r.pubMu.Unlock()
+
+	err := r.ch.ExchangeDeclare(name, amqp.ExchangeFanout, true, false, false, false, nil)
+	if err == nil {
+		r.declaredExchanges[name] = struct{}{}
+	}
+	return err
+}
+
+// declareRequestExchange declares the direct exchange routing requests for
+// ingressID, the first time it's asked for; later calls for the same
+// ingress ID are no-ops, so SendRequest/IngressSubscription don't redeclare
+// it on every call.
+func (r *RabbitMQRPC) declareRequestExchange(ingressID string) (string, error) {
+	name := requestChannel(ingressID)
+
+	r.pubMu.Lock()
+	defer r.pubMu.Unlock()
+
+	if _, ok := r.declaredExchanges[name]; ok {
+		return name, nil
+	}
+	if err := r.ch.ExchangeDeclare(name, amqp.ExchangeDirect, true, false, false, false, nil); err != nil {
+		return "", err
+	}
+	r.declaredExchanges[name] = struct{}{}
+	return name, nil
+}
+
+func (r *RabbitMQRPC) setupReplyQueue() error {
+	r.pubMu.Lock()
+	defer r.pubMu.Unlock()
+
+	q, err := r.ch.QueueDeclare("", false, true, true, false, nil)
+	if err != nil {
+		return err
+	}
+	msgs, err := r.ch.Consume(q.Name, "", true, true, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	r.replyQ = q
+	r.replyCons = msgs
+
+	go r.consumeReplies()
+
+	return nil
+}
+
+func (r *RabbitMQRPC) consumeReplies() {
+	for d := range r.replyCons {
+		r.mu.Lock()
+		ch, ok := r.pending[d.CorrelationId]
+		r.mu.Unlock()
+		if !ok {
+			continue
+		}
+		d := d
+		ch <- &d
+	}
+}
+
+func (r *RabbitMQRPC) sendRequest(
+	ctx context.Context,
+	requestID string,
+	method string,
+	exchange string,
+	request proto.Message,
+	resp proto.Message,
+) (proto.Message, error) {
+	requestsInFlight.Inc()
+	defer requestsInFlight.Dec()
+	start := time.Now()
+
+	body, err := proto.Marshal(request)
+	if err != nil {
+		requestErrors.WithLabelValues(method).Inc()
+		return nil, err
+	}
+
+	replyCh := make(chan *amqp.Delivery, 1)
+	r.mu.Lock()
+	r.pending[requestID] = replyCh
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.pending, requestID)
+		r.mu.Unlock()
+	}()
+
+	r.pubMu.Lock()
+	err = r.ch.PublishWithContext(ctx, exchange, "", false, false, amqp.Publishing{
+		ContentType:   "application/protobuf",
+		CorrelationId: requestID,
+		ReplyTo:       r.replyQ.Name,
+		Body:          body,
+	})
+	r.pubMu.Unlock()
+	if err != nil {
+		requestErrors.WithLabelValues(method).Inc()
+		return nil, err
+	}
+
+	select {
+	case d := <-replyCh:
+		if err := proto.Unmarshal(d.Body, resp); err != nil {
+			requestErrors.WithLabelValues(method).Inc()
+			return nil, err
+		}
+		requestLatency.WithLabelValues(method).Observe(time.Since(start).Seconds())
+		responsesTotal.WithLabelValues(method).Inc()
+		return resp, nil
+
+	case <-time.After(requestTimeout):
+		requestTimeouts.WithLabelValues(method).Inc()
+		return nil, ErrNoResponse
+	}
+}
+
+func (r *RabbitMQRPC) SendRequest(ctx context.Context, req *livekit.IngressRequest) (*livekit.IngressState, error) {
+	requestID := utils.NewGuid(utils.RPCPrefix)
+	req.RequestId = requestID
+	req.SenderId = string(r.nodeID)
+
+	exchange, err := r.declareRequestExchange(req.IngressId)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &livekit.IngressResponse{}
+	if _, err := r.sendRequest(ctx, requestID, methodSendRequest, exchange, req, resp); err != nil {
+		return nil, err
+	} else if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	return resp.State, nil
+}
+
+func (r *RabbitMQRPC) SendGetIngressInfoRequest(ctx context.Context, req *livekit.GetIngressInfoRequest) (*livekit.GetIngressInfoResponse, error) {
+	requestID := utils.NewGuid(utils.RPCPrefix)
+	req.RequestId = requestID
+	req.SenderId = string(r.nodeID)
+	req.SentAt = time.Now().UnixNano()
+
+	resp := &livekit.GetIngressInfoResponse{}
+	if _, err := r.sendRequest(ctx, requestID, methodSendGetIngressInfoRequest, entityChannel, req, resp); err != nil {
+		return nil, err
+	} else if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	return resp, nil
+}
+
+func (r *RabbitMQRPC) replyTo(ctx context.Context, replyTo, correlationID string, msg proto.Message) error {
+	body, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	r.pubMu.Lock()
+	defer r.pubMu.Unlock()
+	return r.ch.PublishWithContext(ctx, "", replyTo, false, false, amqp.Publishing{
+		ContentType:   "application/protobuf",
+		CorrelationId: correlationID,
+		Body:          body,
+	})
+}
+
+// takeReplyAddr returns and forgets the reply queue recorded for
+// correlationID by rememberReplyTo when the originating request was
+// delivered.
+func (r *RabbitMQRPC) takeReplyAddr(correlationID string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	addr, ok := r.replyAddrs[correlationID]
+	if ok {
+		delete(r.replyAddrs, correlationID)
+	}
+	return addr, ok
+}
+
+// rememberReplyTo records the reply queue named in a request delivery's
+// ReplyTo field, keyed by its CorrelationId, so the eventual SendResponse
+// knows where to publish the answer.
+func (r *RabbitMQRPC) rememberReplyTo(d amqp.Delivery) {
+	if d.CorrelationId == "" || d.ReplyTo == "" {
+		return
+	}
+	r.mu.Lock()
+	r.replyAddrs[d.CorrelationId] = d.ReplyTo
+	r.mu.Unlock()
+}
+
+func (r *RabbitMQRPC) SendResponse(ctx context.Context, req *livekit.IngressRequest, state *livekit.IngressState, err error) error {
+	res := &livekit.IngressResponse{
+		State:     state,
+		RequestId: req.RequestId,
+	}
+	if err != nil {
+		res.Error = err.Error()
+	}
+
+	addr, ok := r.takeReplyAddr(req.RequestId)
+	if !ok {
+		return fmt.Errorf("rpc: no reply address recorded for request %s", req.RequestId)
+	}
+	return r.replyTo(ctx, addr, req.RequestId, res)
+}
+
+func (r *RabbitMQRPC) SendGetIngressInfoResponse(ctx context.Context, req *livekit.GetIngressInfoRequest, resp *livekit.GetIngressInfoResponse, err error) error {
+	resp.RequestId = req.RequestId
+	if err != nil {
+		resp.Error = err.Error()
+	}
+
+	addr, ok := r.takeReplyAddr(req.RequestId)
+	if !ok {
+		return fmt.Errorf("rpc: no reply address recorded for request %s", req.RequestId)
+	}
+	return r.replyTo(ctx, addr, req.RequestId, resp)
+}
+
+func (r *RabbitMQRPC) Events(ctx context.Context, ingressID string) (<-chan *livekit.IngressState, error) {
+	return r.events.Events(ctx, ingressID)
+}
+
+func (r *RabbitMQRPC) SendUpdate(ctx context.Context, ingressID string, state *livekit.IngressState) error {
+	updatesPublished.Inc()
+
+	body, err := proto.Marshal(&livekit.UpdateIngressStateRequest{
+		IngressId: ingressID,
+		State:     state,
+	})
+	if err != nil {
+		return err
+	}
+
+	r.pubMu.Lock()
+	defer r.pubMu.Unlock()
+	return r.ch.PublishWithContext(ctx, updateChannel, "", false, false, amqp.Publishing{
+		ContentType: "application/protobuf",
+		Body:        body,
+	})
+}
+
+func (r *RabbitMQRPC) GetUpdateChannel(ctx context.Context) (utils.PubSub, error) {
+	return r.subscribeFanout(ctx, updateChannel)
+}
+
+// GetEntityChannel subscribes to GetIngressInfo requests. All callers share
+// one named queue, so RabbitMQ delivers each request to exactly one of
+// them - fanning it out to every instance, like subscribeFanout does,
+// would mean every instance but the one that actually owns the entity
+// answers with "no reply address recorded".
+func (r *RabbitMQRPC) GetEntityChannel(ctx context.Context) (utils.PubSub, error) {
+	return r.subscribe(ctx, entityChannel, entityChannel, r.rememberReplyTo)
+}
+
+func (r *RabbitMQRPC) IngressSubscription(ctx context.Context, ingressID string) (utils.PubSub, error) {
+	exchange, err := r.declareRequestExchange(ingressID)
+	if err != nil {
+		return nil, err
+	}
+	return r.subscribe(ctx, exchange, "", r.rememberReplyTo)
+}
+
+// subscribeFanout binds a fresh exclusive queue to the named exchange, so
+// every subscriber gets its own copy of every message.
+func (r *RabbitMQRPC) subscribeFanout(ctx context.Context, exchange string) (utils.PubSub, error) {
+	return r.subscribe(ctx, exchange, "", nil)
+}
+
+// subscribe binds queueName to exchange and returns a PubSub reading from
+// it, invoking onDelivery, if set, for every message before delivering it.
+// An empty queueName gets an exclusive, auto-delete queue of its own
+// (broadcast semantics). A fixed queueName is shared across every caller
+// that passes it, so RabbitMQ load-balances each message to exactly one of
+// them instead of broadcasting it.
+func (r *RabbitMQRPC) subscribe(ctx context.Context, exchange, queueName string, onDelivery func(amqp.Delivery)) (utils.PubSub, error) {
+	ch, err := r.conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	exclusive := queueName == ""
+	q, err := ch.QueueDeclare(queueName, false, exclusive, exclusive, false, nil)
+	if err != nil {
+		_ = ch.Close()
+		return nil, err
+	}
+	if err := ch.QueueBind(q.Name, "", exchange, false, nil); err != nil {
+		_ = ch.Close()
+		return nil, err
+	}
+	msgs, err := ch.Consume(q.Name, "", true, false, false, false, nil)
+	if err != nil {
+		_ = ch.Close()
+		return nil, err
+	}
+
+	return newRabbitMQSub(ch, msgs, onDelivery), nil
+}
+
+// rabbitMQSub adapts an amqp delivery channel to utils.PubSub.
+type rabbitMQSub struct {
+	ch   *amqp.Channel
+	msgs <-chan amqp.Delivery
+	out  chan interface{}
+}
+
+func newRabbitMQSub(ch *amqp.Channel, msgs <-chan amqp.Delivery, onDelivery func(amqp.Delivery)) *rabbitMQSub {
+	s := &rabbitMQSub{
+		ch:   ch,
+		msgs: msgs,
+		out:  make(chan interface{}),
+	}
+	go func() {
+		for d := range msgs {
+			if onDelivery != nil {
+				onDelivery(d)
+			}
+			s.out <- d.Body
+		}
+		close(s.out)
+	}()
+	return s
+}
+
+func (s *rabbitMQSub) Channel() <-chan interface{} {
+	return s.out
+}
+
+func (s *rabbitMQSub) Payload(raw interface{}) []byte {
+	return raw.([]byte)
+}
+
+func (s *rabbitMQSub) Close() error {
+	return s.ch.Close()
+}
@@ -0,0 +1,164 @@
+package ingress
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	"github.com/livekit/protocol/utils"
+)
+
+// RedisConfig describes how to connect to Redis for ingress RPC, mirroring
+// the shape used by GitLab workhorse: a single URL, or a Sentinel quorum for
+// master failover, plus the usual auth/pool/TLS knobs.
+type RedisConfig struct {
+	URL string
+
+	// Sentinel, when set, takes precedence over URL: connections are routed
+	// through Sentinel to the current master of SentinelMaster.
+	Sentinel         []string
+	SentinelMaster   string
+	SentinelPassword string
+
+	// ReplicaRead, when set alongside Sentinel, routes subscriptions to a
+	// Sentinel-elected read replica instead of the master, to keep read
+	// traffic off it. Published messages always go to the master
+	// regardless of this setting. Ignored without Sentinel.
+	ReplicaRead bool
+
+	Password string
+	DB       int
+
+	MaxIdle   int
+	MaxActive int
+
+	TLSConfig *tls.Config
+}
+
+// NewRedisRPCFromConfig builds a RedisRPC from a structured RedisConfig. If
+// Sentinel addresses are configured, pub/sub is wired against a
+// FailoverClient, which redials through Sentinel to the current master
+// whenever a connection breaks - including after a failover - so
+// subscribers aren't left pointed at a stale address. A background watcher
+// also forces a fresh subscription as soon as Sentinel reports a
+// +switch-master event, instead of waiting for the old connection to
+// actually break.
+//
+// Cluster-mode awareness is not implemented: Sentinel master/replica
+// topologies are the only deployment this config supports.
+func NewRedisRPCFromConfig(nodeID livekit.NodeID, conf *RedisConfig) (RPC, error) {
+	if conf == nil {
+		return nil, errors.New("rpc: missing redis config")
+	}
+
+	rc, replica, sentinelClient, err := conf.buildClient()
+	if err != nil {
+		return nil, err
+	}
+
+	r, ok := NewRedisRPC(nodeID, rc).(*RedisRPC)
+	if !ok {
+		return nil, errors.New("rpc: failed to build redis rpc")
+	}
+
+	if replica != nil {
+		r.readBus = utils.NewRedisMessageBus(replica).(*utils.RedisMessageBus)
+	}
+
+	if sentinelClient != nil {
+		go watchSentinelFailover(sentinelClient, conf.SentinelMaster, r.events)
+	}
+
+	return r, nil
+}
+
+// buildClient builds the primary read/write client used for everything
+// except subscriptions, and, when Sentinel and ReplicaRead are both set, a
+// second read-only client routed to a replica for subscriptions to use
+// instead.
+func (c *RedisConfig) buildClient() (rc redis.UniversalClient, replica redis.UniversalClient, sentinelClient *redis.SentinelClient, err error) {
+	if len(c.Sentinel) > 0 {
+		if c.SentinelMaster == "" {
+			return nil, nil, nil, errors.New("rpc: sentinel master name is required")
+		}
+
+		rc = redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       c.SentinelMaster,
+			SentinelAddrs:    c.Sentinel,
+			SentinelPassword: c.SentinelPassword,
+			Password:         c.Password,
+			DB:               c.DB,
+			PoolSize:         c.MaxActive,
+			MinIdleConns:     c.MaxIdle,
+			TLSConfig:        c.TLSConfig,
+		})
+
+		sentinelClient = redis.NewSentinelClient(&redis.Options{
+			Addr:      c.Sentinel[0],
+			Password:  c.SentinelPassword,
+			TLSConfig: c.TLSConfig,
+		})
+
+		if c.ReplicaRead {
+			replica = redis.NewFailoverClient(&redis.FailoverOptions{
+				MasterName:       c.SentinelMaster,
+				SentinelAddrs:    c.Sentinel,
+				SentinelPassword: c.SentinelPassword,
+				Password:         c.Password,
+				DB:               c.DB,
+				PoolSize:         c.MaxActive,
+				MinIdleConns:     c.MaxIdle,
+				TLSConfig:        c.TLSConfig,
+				SlaveOnly:        true,
+			})
+		}
+
+		return rc, replica, sentinelClient, nil
+	}
+
+	if c.URL == "" {
+		return nil, nil, nil, errors.New("rpc: missing redis url")
+	}
+
+	rc = redis.NewClient(&redis.Options{
+		Addr:         c.URL,
+		Password:     c.Password,
+		DB:           c.DB,
+		PoolSize:     c.MaxActive,
+		MinIdleConns: c.MaxIdle,
+		TLSConfig:    c.TLSConfig,
+	})
+
+	return rc, nil, nil, nil
+}
+
+// resubscriber is the subset of eventDemuxer watchSentinelFailover needs to
+// force a fresh upstream subscription after a failover.
+type resubscriber interface {
+	Resubscribe(ctx context.Context) error
+}
+
+// watchSentinelFailover logs master changes reported by Sentinel and, for
+// each one, tells events to drop and reopen its upstream subscription
+// rather than waiting for the old connection to time out or error on its
+// own.
+func watchSentinelFailover(sentinelClient *redis.SentinelClient, master string, events resubscriber) {
+	ctx := context.Background()
+	sub := sentinelClient.Subscribe(ctx, "+switch-master")
+	defer sub.Close()
+
+	for msg := range sub.Channel() {
+		logger.Infow("redis sentinel reported a master failover", "master", master, "payload", msg.Payload)
+
+		if events == nil {
+			continue
+		}
+		if err := events.Resubscribe(ctx); err != nil {
+			logger.Errorw("failed to resubscribe ingress events after sentinel failover", err, "master", master)
+		}
+	}
+}